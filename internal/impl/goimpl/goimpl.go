@@ -0,0 +1,27 @@
+// Package goimpl implements docsonnet's Evaluator interface on top of
+// github.com/google/go-jsonnet, the default in-process backend.
+package goimpl
+
+import "github.com/google/go-jsonnet"
+
+// Evaluator wraps a *jsonnet.VM to satisfy docsonnet.Evaluator.
+type Evaluator struct {
+	vm *jsonnet.VM
+}
+
+// New constructs a go-jsonnet backed Evaluator.
+func New() *Evaluator {
+	return &Evaluator{vm: jsonnet.MakeVM()}
+}
+
+func (e *Evaluator) ExtCode(k, v string) {
+	e.vm.ExtCode(k, v)
+}
+
+func (e *Evaluator) Importer(i jsonnet.Importer) {
+	e.vm.Importer(i)
+}
+
+func (e *Evaluator) EvaluateAnonymousSnippet(filename, snippet string) (string, error) {
+	return e.vm.EvaluateAnonymousSnippet(filename, snippet)
+}
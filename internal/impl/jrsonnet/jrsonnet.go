@@ -0,0 +1,183 @@
+// Package jrsonnet implements docsonnet's Evaluator interface by shelling
+// out to the jrsonnet binary. It trades the ability to share an in-process
+// jsonnet.Importer for a reported 5-20x speedup on large docsonnet
+// corpora such as jsonnet-libs/k8s.
+package jrsonnet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/google/go-jsonnet"
+)
+
+// jpathSource is satisfied by importers that expose their filesystem
+// search paths through a JPath method, such as *docsonnet.Importer, so
+// the jrsonnet subprocess can be given equivalent -J flags.
+type jpathSource interface {
+	JPath() []string
+}
+
+// embeddedSource is satisfied by importers that carry embedded assets
+// with no on-disk representation of their own, such as *docsonnet.Importer
+// (doc-util). The jrsonnet subprocess can only see these if they're
+// materialized to a temp directory and added as a -J search path.
+type embeddedSource interface {
+	Embedded() map[string]string
+}
+
+// Evaluator drives a jrsonnet subprocess per evaluation.
+type Evaluator struct {
+	bin     string
+	jpaths  []string
+	extCode map[string]string
+
+	// err captures a failure that happened while configuring the
+	// importer, since Importer itself cannot return one; it surfaces
+	// the next time EvaluateAnonymousSnippet is called.
+	err error
+}
+
+// New constructs a jrsonnet-backed Evaluator. bin is the path to the
+// jrsonnet executable; an empty string resolves "jrsonnet" from $PATH.
+func New(bin string) *Evaluator {
+	if bin == "" {
+		bin = "jrsonnet"
+	}
+	return &Evaluator{bin: bin, extCode: map[string]string{}}
+}
+
+func (e *Evaluator) ExtCode(k, v string) {
+	e.extCode[k] = v
+}
+
+// Importer configures the subprocess's search paths. jrsonnet resolves
+// imports itself against -J search paths rather than an in-process
+// jsonnet.Importer, so i's JPaths are extracted via the jpathSource
+// interface (e.g. *docsonnet.Importer) or, for a *jsonnet.FileImporter,
+// from its JPaths field, and any embedded assets (satisfied via embeddedSource, e.g.
+// doc-util) are materialized to a temp directory added as an extra -J
+// entry. Importers that expose neither leave the subprocess with no
+// search paths, and imports through them will fail at evaluation time.
+func (e *Evaluator) Importer(i jsonnet.Importer) {
+	var jpaths []string
+	switch src := i.(type) {
+	case jpathSource:
+		jpaths = src.JPath()
+	case *jsonnet.FileImporter:
+		jpaths = src.JPaths
+	}
+
+	embedded := map[string]string{}
+	if es, ok := i.(embeddedSource); ok {
+		embedded = es.Embedded()
+	}
+	if len(embedded) == 0 {
+		e.jpaths = jpaths
+		return
+	}
+
+	dir, err := materializeEmbedded(embedded)
+	if err != nil {
+		e.err = fmt.Errorf("jrsonnet: materializing embedded assets: %w", err)
+		return
+	}
+	e.jpaths = append(append([]string{}, jpaths...), dir)
+}
+
+var (
+	materializeMu    sync.Mutex
+	materializedDirs = map[string]string{}
+)
+
+// materializeEmbedded writes embedded, keyed by base file name (e.g.
+// "main.libsonnet"), under a "doc-util" directory inside a fresh temp
+// directory, and returns that temp directory so it can be added as a -J
+// search path: imports of "doc-util/main.libsonnet" then resolve exactly
+// as they would against the in-process embedded importer.
+//
+// The temp directory is cached for the life of the process, keyed by a
+// hash of embedded's contents, instead of being recreated (and leaked) on
+// every Importer() call: doc-util's embedded contents never change within
+// a run, so every Evaluator in a LoadMany/RenderMany pool ends up sharing
+// the one materialized copy.
+func materializeEmbedded(embedded map[string]string) (string, error) {
+	key := hashEmbedded(embedded)
+
+	materializeMu.Lock()
+	defer materializeMu.Unlock()
+
+	if dir, ok := materializedDirs[key]; ok {
+		return dir, nil
+	}
+
+	dir, err := os.MkdirTemp("", "docsonnet-jrsonnet-")
+	if err != nil {
+		return "", err
+	}
+
+	docUtilDir := filepath.Join(dir, "doc-util")
+	if err := os.MkdirAll(docUtilDir, 0o755); err != nil {
+		return "", err
+	}
+	for name, contents := range embedded {
+		if err := os.WriteFile(filepath.Join(docUtilDir, name), []byte(contents), 0o644); err != nil {
+			return "", err
+		}
+	}
+
+	materializedDirs[key] = dir
+	return dir, nil
+}
+
+// hashEmbedded fingerprints embedded's contents so materializeEmbedded can
+// recognize a previously-materialized set of assets regardless of map
+// iteration order.
+func hashEmbedded(embedded map[string]string) string {
+	names := make([]string, 0, len(embedded))
+	for name := range embedded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\x00%s\x00", name, embedded[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// EvaluateAnonymousSnippet pipes snippet to the jrsonnet binary on stdin
+// and returns its stdout as the evaluation result.
+func (e *Evaluator) EvaluateAnonymousSnippet(filename, snippet string) (string, error) {
+	if e.err != nil {
+		return "", e.err
+	}
+
+	args := []string{"--filename", filename}
+	for _, p := range e.jpaths {
+		args = append(args, "-J", p)
+	}
+	for k, v := range e.extCode {
+		args = append(args, "--ext-code", fmt.Sprintf("%s=%s", k, v))
+	}
+	args = append(args, "-")
+
+	cmd := exec.Command(e.bin, args...)
+	cmd.Stdin = bytes.NewReader([]byte(snippet))
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("jrsonnet: %w: %s", err, stderr.String())
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,69 @@
+package docsonnet
+
+import "sync"
+
+// Cache stores fetched HTTP import contents keyed by URL, along with the
+// ETag and Last-Modified the origin server returned, so the Importer can
+// revalidate with If-None-Match/If-Modified-Since instead of
+// re-downloading unchanged files.
+type Cache interface {
+	// Get returns the cached body for url, if present.
+	Get(url string) (body string, ok bool)
+	// ETag returns the last known ETag for url, if present.
+	ETag(url string) (etag string, ok bool)
+	// LastModified returns the last known Last-Modified value for url, if
+	// present.
+	LastModified(url string) (lastModified string, ok bool)
+	// Set stores body, etag and lastModified for url, replacing any
+	// previous entry.
+	Set(url, body, etag, lastModified string)
+}
+
+// memCache is the default, unbounded in-memory Cache implementation.
+type memCache struct {
+	mu      sync.RWMutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	body         string
+	etag         string
+	lastModified string
+}
+
+func newMemCache() *memCache {
+	return &memCache{entries: map[string]cacheEntry{}}
+}
+
+func (c *memCache) Get(url string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.entries[url]
+	return e.body, ok
+}
+
+func (c *memCache) ETag(url string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.entries[url]
+	if !ok || e.etag == "" {
+		return "", false
+	}
+	return e.etag, true
+}
+
+func (c *memCache) LastModified(url string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.entries[url]
+	if !ok || e.lastModified == "" {
+		return "", false
+	}
+	return e.lastModified, true
+}
+
+func (c *memCache) Set(url, body, etag, lastModified string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[url] = cacheEntry{body: body, etag: etag, lastModified: lastModified}
+}
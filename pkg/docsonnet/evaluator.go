@@ -0,0 +1,42 @@
+package docsonnet
+
+import (
+	"fmt"
+
+	"github.com/google/go-jsonnet"
+
+	"github.com/jsonnet-libs/docsonnet/internal/impl/goimpl"
+	"github.com/jsonnet-libs/docsonnet/internal/impl/jrsonnet"
+)
+
+// Evaluator abstracts over the Jsonnet implementation used to run
+// load.libsonnet and render.libsonnet, so that backends other than
+// go-jsonnet can be selected via Opts.Implementation. This mirrors the
+// evaluator abstraction Tanka introduced in #914 to support jrsonnet and
+// sjsonnet as drop-in replacements for go-jsonnet.
+type Evaluator interface {
+	// ExtCode binds the Jsonnet expression v to the external variable k.
+	ExtCode(k, v string)
+	// Importer sets the jsonnet.Importer used to resolve import
+	// statements. Subprocess-driven backends may only support a subset
+	// of importers; see their package docs.
+	Importer(i jsonnet.Importer)
+	// EvaluateAnonymousSnippet evaluates snippet, named filename for
+	// error messages, and returns the result as JSON.
+	EvaluateAnonymousSnippet(filename, snippet string) (string, error)
+}
+
+// newEvaluator constructs the Evaluator selected by opts.Implementation,
+// defaulting to the bundled go-jsonnet backend.
+func newEvaluator(opts Opts) (Evaluator, error) {
+	switch opts.Implementation {
+	case "", "go":
+		return goimpl.New(), nil
+	case "jrsonnet":
+		return jrsonnet.New(opts.ImplementationBin), nil
+	case "sjsonnet":
+		return nil, fmt.Errorf("docsonnet: sjsonnet implementation is not bundled yet")
+	default:
+		return nil, fmt.Errorf("docsonnet: unknown implementation %q", opts.Implementation)
+	}
+}
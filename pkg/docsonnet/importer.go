@@ -0,0 +1,351 @@
+package docsonnet
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-jsonnet"
+)
+
+// Importer is a pluggable, universal Jsonnet importer for docsonnet. It
+// composes the filesystem-based jsonnet.FileImporter (for relative imports
+// and Opts.JPath lookups), one or more sets of embedded assets (for
+// doc-util and similar bundled libraries), and, optionally, HTTP(S)
+// sources so that `import "https://..."` resolves without a `jb vendor`
+// step.
+//
+// The zero value is not usable; construct one with NewImporter.
+type Importer struct {
+	fi   jsonnet.FileImporter
+	fiMu sync.Mutex
+
+	mu          sync.Mutex
+	embedded    map[string]jsonnet.Contents
+	httpSources []string
+	client      *http.Client
+	cache       Cache
+	yamlCache   map[string]jsonnet.Contents
+	fileCache   *fileLRU
+}
+
+// NewImporter constructs an Importer seeded with the doc-util assets
+// bundled in opts.EmbeddedFS, the filesystem lookup paths in opts.JPath,
+// and any URLs found amongst opts.JPath registered as HTTP sources.
+func NewImporter(opts Opts) (*Importer, error) {
+	var fsJPath []string
+	for _, p := range opts.JPath {
+		if !isURL(p) {
+			fsJPath = append(fsJPath, p)
+		}
+	}
+
+	imp := &Importer{
+		fi:        jsonnet.FileImporter{JPaths: fsJPath},
+		embedded:  map[string]jsonnet.Contents{},
+		client:    &http.Client{Timeout: 30 * time.Second},
+		cache:     newMemCache(),
+		yamlCache: map[string]jsonnet.Contents{},
+		fileCache: newFileLRU(opts.CacheSize),
+	}
+
+	if err := imp.AddEmbedded("doc-util", opts.EmbeddedFS); err != nil {
+		return nil, err
+	}
+
+	for _, p := range opts.JPath {
+		if isURL(p) {
+			imp.AddHTTPSource(p)
+		}
+	}
+
+	return imp, nil
+}
+
+// AddEmbedded registers every *.libsonnet file found under dir in fsys,
+// keyed by its base file name, so that imports starting with "doc-util/"
+// or "github.com/jsonnet-libs/docsonnet/doc-util/" resolve to the embedded
+// copy instead of hitting the filesystem. This is how
+// doc-util/main.libsonnet and doc-util/render.libsonnet ship inside the
+// docsonnet binary. Only those doc-util prefixes are looked up among the
+// embedded files, whatever dir the files were registered from, and a file
+// replaces any earlier one with the same base name.
+func (i *Importer) AddEmbedded(dir string, fsys fs.FS) error {
+	return fs.WalkDir(fsys, dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".libsonnet") {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+
+		i.mu.Lock()
+		i.embedded[filepath.Base(path)] = jsonnet.MakeContents(string(data))
+		i.mu.Unlock()
+		return nil
+	})
+}
+
+// AddHTTPSource registers baseURL (e.g.
+// "https://raw.githubusercontent.com/jsonnet-libs/docsonnet/master/") as a
+// remote search path: imports that are themselves absolute http(s) URLs,
+// or that resolve against baseURL the same way a relative import resolves
+// against a JPath entry, are fetched over HTTP instead of from disk.
+func (i *Importer) AddHTTPSource(baseURL string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.httpSources = append(i.httpSources, strings.TrimSuffix(baseURL, "/")+"/")
+}
+
+// SetCache swaps in a custom content cache, keyed by URL, for HTTP
+// imports. The default is an unbounded in-memory cache; callers that need
+// eviction or persistence across processes can provide their own.
+func (i *Importer) SetCache(c Cache) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.cache = c
+}
+
+// JPath returns the importer's filesystem search paths. Subprocess-driven
+// Evaluators (e.g. internal/impl/jrsonnet) use this to configure their own
+// -J flags to match, since they resolve imports themselves instead of
+// going through Import.
+func (i *Importer) JPath() []string {
+	return i.fi.JPaths
+}
+
+// Embedded returns a copy of the embedded file contents keyed by base
+// file name (e.g. "main.libsonnet"), so subprocess Evaluators with no way
+// to share this in-process data can materialize it to a temp directory
+// and add that as a search path of their own.
+func (i *Importer) Embedded() map[string]string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	out := make(map[string]string, len(i.embedded))
+	for k, v := range i.embedded {
+		out[k] = v.String()
+	}
+	return out
+}
+
+var docUtilPathPrefixes = []string{
+	"doc-util/",
+	"github.com/jsonnet-libs/docsonnet/doc-util/",
+	"./render.libsonnet",
+}
+
+// Import implements jsonnet.Importer.
+func (i *Importer) Import(importedFrom, importedPath string) (contents jsonnet.Contents, foundAt string, err error) {
+	if isPseudoPath(importedPath) {
+		// <extvar:...>, <top-level-arg:...>, etc. are synthetic names
+		// go-jsonnet uses to label an ExtCode/TLA expression, not real
+		// import targets; reject them with a clean error instead of
+		// letting the file importer fail looking for a literal file
+		// named "<extvar:d>".
+		return jsonnet.Contents{}, "", fmt.Errorf("docsonnet: cannot import pseudo path %q", importedPath)
+	}
+
+	for _, p := range docUtilPathPrefixes {
+		if strings.HasPrefix(importedPath, p) {
+			return i.loadFromEmbed(importedPath)
+		}
+	}
+
+	if isURL(importedPath) {
+		return i.loadFromHTTP(importedPath)
+	}
+
+	if isURL(importedFrom) {
+		return i.loadFromHTTP(joinURL(importedFrom, importedPath))
+	}
+
+	load := i.loadFromDisk
+	if isDataFile(importedPath) {
+		load = i.loadFromDataFile
+	}
+
+	contents, foundAt, err = load(importedFrom, importedPath)
+	if err == nil || isRelativeImport(importedPath) {
+		// Either resolved on disk, or explicitly relative ("./", "../"):
+		// a relative import is never meant to be shadowed by an
+		// unrelated HTTP source, so don't try one.
+		return contents, foundAt, err
+	}
+
+	for _, base := range i.httpSources {
+		if c, f, e := i.loadFromHTTP(joinURL(base, importedPath)); e == nil {
+			return c, f, nil
+		}
+	}
+	return contents, foundAt, err
+}
+
+// isRelativeImport reports whether importedPath is explicitly relative to
+// the importing file, as opposed to a bare library name that JPath/HTTP
+// sources are meant to search for.
+func isRelativeImport(importedPath string) bool {
+	return strings.HasPrefix(importedPath, "./") || strings.HasPrefix(importedPath, "../")
+}
+
+// loadFromDisk resolves importedPath the same way jsonnet.FileImporter
+// would, but checks the shared fileLRU first when the import resolves
+// under a JPath entry rather than next to importedFrom, so the same
+// vendored library imported by many LoadMany entry points is only read
+// and Jsonnet-parsed once.
+func (i *Importer) loadFromDisk(importedFrom, importedPath string) (contents jsonnet.Contents, foundAt string, err error) {
+	if _, localErr := os.Stat(filepath.Join(filepath.Dir(importedFrom), importedPath)); localErr == nil {
+		// FileImporter finds this copy before any JPath entry.
+		return i.importFile(importedFrom, importedPath)
+	}
+
+	path, ok := resolveJPath(importedPath, i.fi.JPaths)
+	if !ok {
+		return i.importFile(importedFrom, importedPath)
+	}
+
+	fi, statErr := os.Stat(path)
+	if statErr != nil {
+		return i.importFile(importedFrom, importedPath)
+	}
+	keyPath := path
+	if abs, absErr := filepath.Abs(path); absErr == nil {
+		keyPath = abs
+	}
+	key := fmt.Sprintf("%s@%d", keyPath, fi.ModTime().UnixNano())
+
+	if cached, ok := i.fileCache.get(key); ok {
+		return cached, path, nil
+	}
+
+	contents, foundAt, err = i.importFile(importedFrom, importedPath)
+	if err == nil {
+		i.fileCache.set(key, contents)
+	}
+	return contents, foundAt, err
+}
+
+// importFile is the only place that may call i.fi.Import: go-jsonnet's
+// FileImporter keeps an unsynchronized internal cache of files it has
+// already opened, so it isn't safe to call concurrently. LoadMany and
+// RenderMany share a single Importer across a pool of concurrently
+// running Evaluators, so without this lock two workers resolving disk
+// imports at the same time can corrupt that cache (observable as a
+// "concurrent map writes" crash).
+func (i *Importer) importFile(importedFrom, importedPath string) (jsonnet.Contents, string, error) {
+	i.fiMu.Lock()
+	defer i.fiMu.Unlock()
+	return i.fi.Import(importedFrom, importedPath)
+}
+
+// resolveJPath looks for importedPath under each of jpaths, the JPath part
+// of the search jsonnet.FileImporter performs; the importing file's own
+// directory, which FileImporter tries first, is not checked here. It
+// returns false for imports that start with "./" or "../", since those
+// resolve against the importing file's directory instead.
+func resolveJPath(importedPath string, jpaths []string) (string, bool) {
+	if strings.HasPrefix(importedPath, "./") || strings.HasPrefix(importedPath, "../") || filepath.IsAbs(importedPath) {
+		return "", false
+	}
+	for _, jpath := range jpaths {
+		candidate := filepath.Join(jpath, importedPath)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+func (i *Importer) loadFromEmbed(importedPath string) (contents jsonnet.Contents, foundAt string, err error) {
+	fbase := filepath.Base(importedPath)
+	fpath := filepath.Join("doc-util", fbase)
+	loadPath := fmt.Sprintf("<internal>/%s", fpath)
+
+	i.mu.Lock()
+	conts, hasConts := i.embedded[fbase]
+	i.mu.Unlock()
+	if !hasConts {
+		return jsonnet.Contents{}, loadPath, fmt.Errorf("%s does not exist", fpath)
+	}
+	return conts, loadPath, nil
+}
+
+func (i *Importer) loadFromHTTP(importedURL string) (contents jsonnet.Contents, foundAt string, err error) {
+	cachedBody, haveCached := i.cache.Get(importedURL)
+
+	req, err := http.NewRequest(http.MethodGet, importedURL, nil)
+	if err != nil {
+		return jsonnet.Contents{}, importedURL, err
+	}
+	if etag, ok := i.cache.ETag(importedURL); ok {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified, ok := i.cache.LastModified(importedURL); ok {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		if haveCached {
+			// Origin unreachable: serve the last known-good copy
+			// rather than failing the whole import.
+			return jsonnet.MakeContents(cachedBody), importedURL, nil
+		}
+		return jsonnet.Contents{}, importedURL, fmt.Errorf("fetching %s: %w", importedURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && haveCached {
+		return jsonnet.MakeContents(cachedBody), importedURL, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return jsonnet.Contents{}, importedURL, fmt.Errorf("fetching %s: unexpected status %s", importedURL, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return jsonnet.Contents{}, importedURL, fmt.Errorf("reading %s: %w", importedURL, err)
+	}
+
+	i.cache.Set(importedURL, string(body), resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"))
+	return jsonnet.MakeContents(string(body)), importedURL, nil
+}
+
+// isURL reports whether p is an absolute http(s) URL rather than a
+// filesystem path.
+func isURL(p string) bool {
+	return strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://")
+}
+
+// isPseudoPath reports whether p is one of go-jsonnet's synthetic import
+// origins, such as "<extvar:d>" or "<top-level-arg:main>", which have no
+// directory to resolve relative imports against.
+func isPseudoPath(p string) bool {
+	return strings.HasPrefix(p, "<") && strings.HasSuffix(p, ">")
+}
+
+// joinURL resolves importedPath against base the way a relative import is
+// resolved against the directory of the file that imported it.
+func joinURL(base, importedPath string) string {
+	if isURL(importedPath) {
+		return importedPath
+	}
+	dir := base
+	if !isURL(dir) {
+		dir = filepath.Dir(dir) + "/"
+	} else if !strings.HasSuffix(dir, "/") {
+		dir = dir[:strings.LastIndex(dir, "/")+1]
+	}
+	return dir + importedPath
+}
@@ -0,0 +1,57 @@
+package docsonnet
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/jsonnet-libs/docsonnet/pkg/importers"
+)
+
+// FindImporters walks root's Jsonnet tree and returns every file that
+// transitively imports one of changedFiles, plus changedFiles themselves.
+// This is docsonnet's answer to Tanka's `tk tool importers`: given the
+// files a CI run touched, it tells you which docsonnet entry points need
+// to be re-rendered, instead of re-rendering the whole tree.
+//
+// When opts.ImportersCachePath is set, the reverse import graph is cached
+// to disk across invocations, keyed by (path, size, mtime) per file, so
+// repeated CI runs only re-parse what changed.
+func FindImporters(root string, changedFiles []string, opts Opts) ([]string, error) {
+	graph, err := importers.Build(root, opts.JPath, opts.ImportersCachePath)
+	if err != nil {
+		return nil, err
+	}
+
+	affected := map[string]struct{}{}
+	queue := make([]string, 0, len(changedFiles))
+	for _, f := range changedFiles {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := affected[abs]; !ok {
+			affected[abs] = struct{}{}
+			queue = append(queue, abs)
+		}
+	}
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		for importer := range graph[cur] {
+			if _, ok := affected[importer]; ok {
+				continue
+			}
+			affected[importer] = struct{}{}
+			queue = append(queue, importer)
+		}
+	}
+
+	out := make([]string, 0, len(affected))
+	for f := range affected {
+		out = append(out, f)
+	}
+	sort.Strings(out)
+	return out, nil
+}
@@ -4,16 +4,33 @@ import (
 	"embed"
 	"encoding/json"
 	"fmt"
-	"log"
-	"path/filepath"
-	"strings"
-
-	"github.com/google/go-jsonnet"
 )
 
 type Opts struct {
 	JPath      []string
 	EmbeddedFS embed.FS
+
+	// Implementation selects the Jsonnet backend used to evaluate
+	// load.libsonnet/render.libsonnet: "go" (default) uses the bundled
+	// go-jsonnet, "jrsonnet" shells out to a jrsonnet binary, and
+	// "sjsonnet" is reserved for a future JVM-subprocess backend.
+	Implementation string
+	// ImplementationBin overrides the executable used by subprocess
+	// backends (e.g. "jrsonnet"). Defaults to resolving the backend's
+	// name from $PATH.
+	ImplementationBin string
+
+	// Concurrency bounds the number of prewarmed Evaluators LoadMany and
+	// RenderMany run in parallel. Defaults to runtime.NumCPU().
+	Concurrency int
+	// CacheSize bounds the number of imported files LoadMany and
+	// RenderMany keep in their shared LRU cache. Defaults to
+	// defaultFileCacheSize.
+	CacheSize int
+
+	// ImportersCachePath, when set, is where FindImporters persists its
+	// reverse import graph between invocations.
+	ImportersCachePath string
 }
 
 // RenderWithJsonnet uses the jsonnet render function to generate the docs, instead of the golang utilities.
@@ -43,6 +60,54 @@ func RenderWithJsonnet(filename string, opts Opts) (map[string]string, error) {
 	return out, err
 }
 
+// RenderSchemas is RenderWithJsonnet's schema counterpart: it drives
+// schema.libsonnet, the render.libsonnet sibling that walks the same
+// docsonnet tree, to produce a JSON Schema document per rendered node,
+// plus a single OpenAPI 3.1 components bundle (suitable for JSON-Schema-
+// backed autocomplete in editors) collecting all of them.
+func RenderSchemas(filename string, opts Opts) (schemas map[string]string, openAPI map[string]interface{}, err error) {
+	schemaLib, err := opts.EmbeddedFS.ReadFile("schema.libsonnet")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vm, err := newVM(filename, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	vm.ExtCode("d", `(import "doc-util/main.libsonnet")`)
+
+	data, err := vm.EvaluateAnonymousSnippet("schema.libsonnet", string(schemaLib))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &out); err != nil {
+		return nil, nil, err
+	}
+
+	schemas = make(map[string]string, len(out))
+	components := make(map[string]interface{}, len(out))
+	for name, raw := range out {
+		schemas[name] = string(raw)
+
+		var schema interface{}
+		if err := json.Unmarshal(raw, &schema); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		components[name] = schema
+	}
+
+	openAPI = map[string]interface{}{
+		"openapi": "3.1.0",
+		"components": map[string]interface{}{
+			"schemas": components,
+		},
+	}
+	return schemas, openAPI, nil
+}
+
 // Load extracts and transforms the docsonnet data in `filename`, returning the
 // top level docsonnet package.
 func Load(filename string, opts Opts) (*Package, error) {
@@ -85,76 +150,26 @@ func Extract(filename string, opts Opts) ([]byte, error) {
 func Transform(data []byte) (*Package, error) {
 	var d ds
 	if err := json.Unmarshal([]byte(data), &d); err != nil {
-		log.Fatalln(err)
+		return nil, err
 	}
 
 	p := fastLoad(d)
 	return &p, nil
 }
 
-// newVM sets up the Jsonnet VM with the importer that statically provides doc-util.
-func newVM(mainFName string, opts Opts) (*jsonnet.VM, error) {
-	vm := jsonnet.MakeVM()
-	imp, err := newImporter(opts)
+// newVM sets up a Jsonnet Evaluator, using the backend selected by
+// opts.Implementation, with the importer that statically provides
+// doc-util.
+func newVM(mainFName string, opts Opts) (Evaluator, error) {
+	vm, err := newEvaluator(opts)
 	if err != nil {
 		return nil, err
 	}
-	vm.Importer(imp)
-	vm.ExtCode("main", fmt.Sprintf(`(import "%s")`, mainFName))
-	return vm, nil
-}
-
-// importer wraps jsonnet.FileImporter, to statically provide doc-util,
-// bundled with the binary
-type importer struct {
-	fi       jsonnet.FileImporter
-	embedded map[string]jsonnet.Contents
-}
-
-func newImporter(opts Opts) (*importer, error) {
-	dmain, err := opts.EmbeddedFS.ReadFile("doc-util/main.libsonnet")
-	if err != nil {
-		return nil, err
-	}
-	drender, err := opts.EmbeddedFS.ReadFile("doc-util/render.libsonnet")
+	imp, err := NewImporter(opts)
 	if err != nil {
 		return nil, err
 	}
-	embedded := map[string]jsonnet.Contents{
-		"main.libsonnet":   jsonnet.MakeContents(string(dmain)),
-		"render.libsonnet": jsonnet.MakeContents(string(drender)),
-	}
-
-	return &importer{
-		fi:       jsonnet.FileImporter{JPaths: opts.JPath},
-		embedded: embedded,
-	}, nil
-}
-
-var docUtilPathPrefixes = []string{
-	"doc-util/",
-	"github.com/jsonnet-libs/docsonnet/doc-util/",
-	"./render.libsonnet",
-}
-
-func (i *importer) Import(importedFrom, importedPath string) (contents jsonnet.Contents, foundAt string, err error) {
-	for _, p := range docUtilPathPrefixes {
-		if strings.HasPrefix(importedPath, p) {
-			return i.loadFromEmbed(importedPath)
-		}
-	}
-
-	return i.fi.Import(importedFrom, importedPath)
-}
-
-func (i *importer) loadFromEmbed(importedPath string) (contents jsonnet.Contents, foundAt string, err error) {
-	fbase := filepath.Base(importedPath)
-	fpath := filepath.Join("doc-util", fbase)
-	loadPath := fmt.Sprintf("<internal>/%s", fpath)
-
-	conts, hasConts := i.embedded[fbase]
-	if !hasConts {
-		return jsonnet.Contents{}, loadPath, fmt.Errorf("%s does not exist", fpath)
-	}
-	return conts, loadPath, nil
+	vm.Importer(imp)
+	vm.ExtCode("main", fmt.Sprintf(`(import "%s")`, mainFName))
+	return vm, nil
 }
@@ -0,0 +1,74 @@
+package docsonnet
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/google/go-jsonnet"
+)
+
+// defaultFileCacheSize bounds the shared file-content cache used by
+// LoadMany/RenderMany when Opts.CacheSize is left at its zero value.
+const defaultFileCacheSize = 1024
+
+// fileLRU is a fixed-capacity LRU cache of imported file contents, bounded
+// by entry count and keyed by absolute path + mtime, so a changed file gets
+// a new key and its stale entry is no longer returned; the stale entry
+// stays in the cache until it is evicted as least recently used.
+type fileLRU struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type fileLRUEntry struct {
+	key      string
+	contents jsonnet.Contents
+}
+
+func newFileLRU(capacity int) *fileLRU {
+	if capacity <= 0 {
+		capacity = defaultFileCacheSize
+	}
+	return &fileLRU{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+func (c *fileLRU) get(key string) (jsonnet.Contents, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return jsonnet.Contents{}, false
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*fileLRUEntry).contents, true
+}
+
+func (c *fileLRU) set(key string, contents jsonnet.Contents) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		el.Value.(*fileLRUEntry).contents = contents
+		return
+	}
+
+	el := c.ll.PushFront(&fileLRUEntry{key: key, contents: contents})
+	c.items[key] = el
+
+	for c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		if oldest == nil {
+			break
+		}
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*fileLRUEntry).key)
+	}
+}
@@ -0,0 +1,187 @@
+package docsonnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// LoadMany evaluates each of filenames concurrently, sharing a single
+// Importer instance and a bounded pool of prewarmed Evaluators across all
+// of them, and returns each file's loaded Package keyed by filename.
+//
+// This turns the O(N*t) wall-clock of looping Load over hundreds of
+// monorepo entry points into roughly O(N*t/cores), while Opts.Concurrency
+// and Opts.CacheSize keep worker and memory usage bounded. Cancelling ctx
+// stops evaluations that have not started yet; an evaluation already
+// running is not interrupted and runs to completion.
+func LoadMany(ctx context.Context, filenames []string, opts Opts) (map[string]*Package, error) {
+	out := make(map[string]*Package, len(filenames))
+	var mu sync.Mutex
+
+	err := runMany(ctx, filenames, opts, func(vm Evaluator, filename string) error {
+		data, err := extractWithEvaluator(vm, filename, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		pkg, err := Transform(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+
+		mu.Lock()
+		out[filename] = pkg
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RenderMany is the LoadMany counterpart of RenderWithJsonnet: it renders
+// every file in filenames to its Markdown output map, concurrently, using
+// the same kind of worker pool and shared Importer as LoadMany. Each call
+// builds its own pool and Importer.
+func RenderMany(ctx context.Context, filenames []string, opts Opts) (map[string]map[string]string, error) {
+	out := make(map[string]map[string]string, len(filenames))
+	var mu sync.Mutex
+
+	err := runMany(ctx, filenames, opts, func(vm Evaluator, filename string) error {
+		rendered, err := renderWithEvaluator(vm, filename, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+
+		mu.Lock()
+		out[filename] = rendered
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// runMany fans job out across a bounded pool of prewarmed Evaluators that
+// all share one Importer, and waits for every filename to be processed or
+// ctx to be cancelled, whichever comes first.
+func runMany(ctx context.Context, filenames []string, opts Opts, job func(vm Evaluator, filename string) error) error {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	if concurrency > len(filenames) {
+		concurrency = len(filenames)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	imp, err := NewImporter(opts)
+	if err != nil {
+		return err
+	}
+
+	vms := make(chan Evaluator, concurrency)
+	for n := 0; n < concurrency; n++ {
+		vm, err := newEvaluator(opts)
+		if err != nil {
+			return err
+		}
+		vm.Importer(imp)
+		vms <- vm
+	}
+
+	filesCh := make(chan string)
+	go func() {
+		defer close(filesCh)
+		for _, f := range filenames {
+			select {
+			case filesCh <- f:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for n := 0; n < concurrency; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for filename := range filesCh {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+
+				vm := <-vms
+				err := job(vm, filename)
+				vms <- vm
+
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// extractWithEvaluator is Extract, minus constructing a fresh Evaluator:
+// it reuses vm, which already has its Importer set, so LoadMany can pull
+// vm from a shared pool instead of paying VM startup cost per file.
+func extractWithEvaluator(vm Evaluator, filename string, opts Opts) ([]byte, error) {
+	load, err := opts.EmbeddedFS.ReadFile("load.libsonnet")
+	if err != nil {
+		return nil, err
+	}
+
+	vm.ExtCode("main", fmt.Sprintf(`(import "%s")`, filename))
+	data, err := vm.EvaluateAnonymousSnippet("load.libsonnet", string(load))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(data), nil
+}
+
+// renderWithEvaluator is RenderWithJsonnet's counterpart to
+// extractWithEvaluator.
+func renderWithEvaluator(vm Evaluator, filename string, opts Opts) (map[string]string, error) {
+	render, err := opts.EmbeddedFS.ReadFile("render.libsonnet")
+	if err != nil {
+		return nil, err
+	}
+
+	vm.ExtCode("main", fmt.Sprintf(`(import "%s")`, filename))
+	vm.ExtCode("d", `(import "doc-util/main.libsonnet")`)
+	data, err := vm.EvaluateAnonymousSnippet("render.libsonnet", string(render))
+	if err != nil {
+		return nil, err
+	}
+
+	var out map[string]string
+	if err := json.Unmarshal([]byte(data), &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
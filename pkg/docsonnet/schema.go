@@ -0,0 +1,164 @@
+package docsonnet
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ToJSONSchema derives a JSON Schema describing the shape of the library
+// p documents -- not p's own Go metadata -- by walking its d.fn/d.obj/
+// d.val tree: objects recurse into their nested fields, function
+// parameters are typed from their documented default (or `enum`, when
+// annotated with one), and values are typed from their documented
+// default. A `#withFoo` setter and the `foo` field it configures are
+// collapsed into one property, the same way render.libsonnet documents
+// the two as a single entry in Markdown.
+func (p *Package) ToJSONSchema() (map[string]interface{}, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var node map[string]interface{}
+	if err := json.Unmarshal(raw, &node); err != nil {
+		return nil, err
+	}
+
+	return objectSchema(node), nil
+}
+
+// objectSchema builds an "object" schema from a docsonnet package/object
+// node: its help text becomes "description", and each entry in its
+// "fields" becomes a property, recursively schematized by fieldSchema.
+func objectSchema(node map[string]interface{}) map[string]interface{} {
+	schema := map[string]interface{}{"type": "object"}
+	if help, ok := node["help"].(string); ok && help != "" {
+		schema["description"] = help
+	}
+
+	fields, _ := node["fields"].([]interface{})
+	props := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		field, ok := f.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := field["name"].(string)
+		if name == "" {
+			continue
+		}
+		props[collapseSetterKey(name)] = fieldSchema(field)
+	}
+	if len(props) > 0 {
+		schema["properties"] = props
+	}
+	return schema
+}
+
+// fieldSchema schematizes a single docsonnet field, dispatching on its
+// "type" discriminator ("object", "function", or "value").
+func fieldSchema(field map[string]interface{}) map[string]interface{} {
+	switch field["type"] {
+	case "object":
+		return objectSchema(field)
+	case "function":
+		return functionSchema(field)
+	default:
+		return valueSchema(field)
+	}
+}
+
+// functionSchema turns a documented function's params into an object
+// schema, one property per param, typed from its default (or enum, when
+// the param was annotated with one).
+func functionSchema(field map[string]interface{}) map[string]interface{} {
+	schema := map[string]interface{}{"type": "object"}
+	if help, ok := field["help"].(string); ok && help != "" {
+		schema["description"] = help
+	}
+
+	params, _ := field["params"].([]interface{})
+	props := make(map[string]interface{}, len(params))
+	for _, p := range params {
+		param, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := param["name"].(string)
+		if name == "" {
+			continue
+		}
+		props[name] = paramSchema(param)
+	}
+	if len(props) > 0 {
+		schema["properties"] = props
+	}
+	return schema
+}
+
+// paramSchema infers a function parameter's JSON Schema type from its
+// documented default value, and attaches "enum"/"default" when present.
+func paramSchema(param map[string]interface{}) map[string]interface{} {
+	schema := typeSchema(param["default"])
+	if enum, ok := param["enum"].([]interface{}); ok && len(enum) > 0 {
+		schema["enum"] = enum
+	}
+	if def, ok := param["default"]; ok && def != nil {
+		schema["default"] = def
+	}
+	return schema
+}
+
+// valueSchema infers a documented value's JSON Schema type from its
+// default, falling back to "string" when the library didn't document one.
+func valueSchema(field map[string]interface{}) map[string]interface{} {
+	schema := typeSchema(field["default"])
+	if help, ok := field["help"].(string); ok && help != "" {
+		schema["description"] = help
+	}
+	if def, ok := field["default"]; ok && def != nil {
+		schema["default"] = def
+	}
+	return schema
+}
+
+// typeSchema infers a JSON Schema "type" from a concrete documented
+// value, recursing into objects and arrays instead of collapsing every
+// field down to "string".
+func typeSchema(v interface{}) map[string]interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		props := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			props[k] = typeSchema(child)
+		}
+		return map[string]interface{}{"type": "object", "properties": props}
+	case []interface{}:
+		if len(val) == 0 {
+			return map[string]interface{}{"type": "array"}
+		}
+		return map[string]interface{}{"type": "array", "items": typeSchema(val[0])}
+	case string:
+		return map[string]interface{}{"type": "string"}
+	case bool:
+		return map[string]interface{}{"type": "boolean"}
+	case float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		// No documented default to infer a type from (e.g. a required
+		// function parameter); "string" is the most permissive JSON
+		// Schema scalar type to fall back to.
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+// collapseSetterKey folds a "#withFoo" setter-chain key down to "foo", the
+// argument name it ultimately sets, so the schema exposes one logical
+// property instead of the setter method alongside it.
+func collapseSetterKey(k string) string {
+	name := strings.TrimPrefix(k, "#with")
+	if name == k || name == "" {
+		return k
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
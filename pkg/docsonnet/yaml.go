@@ -0,0 +1,104 @@
+package docsonnet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+	yamlv3 "gopkg.in/yaml.v3"
+	"sigs.k8s.io/yaml"
+)
+
+// isDataFile reports whether importedPath names a YAML or JSON fixture
+// that should be converted to a plain Jsonnet object/array rather than
+// parsed as Jsonnet source.
+func isDataFile(importedPath string) bool {
+	switch strings.ToLower(filepath.Ext(importedPath)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// loadFromDataFile reads importedPath through the wrapped FileImporter and
+// converts it to JSON, so `import "fixture.yaml"` yields a plain object
+// (or, for multi-document streams, an array of objects) the same way
+// `import "fixture.json"` already does. Converted results are cached by
+// source path and mtime so repeated imports of the same fixture are free.
+func (i *Importer) loadFromDataFile(importedFrom, importedPath string) (contents jsonnet.Contents, foundAt string, err error) {
+	raw, foundAt, err := i.importFile(importedFrom, importedPath)
+	if err != nil {
+		return jsonnet.Contents{}, foundAt, err
+	}
+
+	key := foundAt
+	if fi, statErr := os.Stat(foundAt); statErr == nil {
+		key = fmt.Sprintf("%s@%d", foundAt, fi.ModTime().UnixNano())
+	}
+
+	i.mu.Lock()
+	cached, ok := i.yamlCache[key]
+	i.mu.Unlock()
+	if ok {
+		return cached, foundAt, nil
+	}
+
+	converted, err := yamlToJSON([]byte(raw.String()))
+	if err != nil {
+		return jsonnet.Contents{}, foundAt, fmt.Errorf("converting %s to JSON: %w", foundAt, err)
+	}
+	out := jsonnet.MakeContents(string(converted))
+
+	i.mu.Lock()
+	i.yamlCache[key] = out
+	i.mu.Unlock()
+	return out, foundAt, nil
+}
+
+// yamlToJSON converts a YAML (or JSON, which is valid YAML) document
+// stream to JSON. A single-document stream yields a JSON object/array/
+// scalar; a multi-document stream (separated by "---") yields a JSON
+// array of the decoded documents.
+//
+// Each document is converted through sigs.k8s.io/yaml, which round-trips
+// via encoding/json struct tags, rather than gopkg.in/yaml.v3 decoding
+// straight into interface{}: the latter produces map[interface{}]interface{}
+// for any document with a non-string key (e.g. a numeric or boolean map
+// key), which json.Marshal then refuses to encode. yaml.v3 is still used
+// here, but only to split the stream into individual documents (as
+// yaml.Node, never decoded into a Go map), each of which is then handed to
+// sigs.k8s.io/yaml on its own.
+func yamlToJSON(data []byte) ([]byte, error) {
+	dec := yamlv3.NewDecoder(bytes.NewReader(data))
+
+	var docs []json.RawMessage
+	for {
+		var node yamlv3.Node
+		if err := dec.Decode(&node); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+
+		raw, err := yamlv3.Marshal(&node)
+		if err != nil {
+			return nil, err
+		}
+		converted, err := yaml.YAMLToJSON(raw)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, converted)
+	}
+
+	if len(docs) == 1 {
+		return docs[0], nil
+	}
+	return json.Marshal(docs)
+}
@@ -0,0 +1,56 @@
+package importers
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// fileEntry is a single file's cached parse result: its fingerprint at
+// the time it was last parsed, and the absolute paths it imports.
+type fileEntry struct {
+	Fingerprint string   `json:"fingerprint"`
+	Imports     []string `json:"imports"`
+}
+
+// diskCache is the on-disk representation of Build's cache, keyed by
+// absolute file path.
+type diskCache struct {
+	Files map[string]fileEntry `json:"files"`
+}
+
+// loadCache reads the cache at path, returning an empty cache if path is
+// unset or the file doesn't exist or is unreadable garbage.
+func loadCache(path string) (*diskCache, error) {
+	c := &diskCache{Files: map[string]fileEntry{}}
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		// A corrupt cache shouldn't fail the build: fall back to
+		// parsing everything from scratch.
+		return &diskCache{Files: map[string]fileEntry{}}, nil
+	}
+	return c, nil
+}
+
+// saveCache persists c to path. It is a no-op when path is unset.
+func saveCache(path string, c *diskCache) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,181 @@
+// Package importers builds and queries a reverse import graph over a tree
+// of Jsonnet files, answering "which files would be affected if these
+// files changed?" the same way Tanka's `tk tool importers` does for
+// Grafonnet/Jsonnet monorepos.
+package importers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+	"github.com/google/go-jsonnet/toolutils"
+)
+
+// Graph is a reverse import graph: Graph[imported] holds the set of
+// absolute paths that directly import it.
+type Graph map[string]map[string]struct{}
+
+// Add records that importer directly imports imported.
+func (g Graph) Add(imported, importer string) {
+	edges, ok := g[imported]
+	if !ok {
+		edges = map[string]struct{}{}
+		g[imported] = edges
+	}
+	edges[importer] = struct{}{}
+}
+
+// Build walks root for *.libsonnet files, parses each one's imports
+// (resolving them against jpaths the same way the real importer would,
+// not just relative to the importing file), and returns the resulting
+// reverse Graph. If cachePath is non-empty, Build reuses previously
+// parsed edges for files whose fingerprint (path, mtime, size, jpaths)
+// hasn't changed, and persists the updated cache back to cachePath.
+func Build(root string, jpaths []string, cachePath string) (Graph, error) {
+	cache, err := loadCache(cachePath)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+
+	err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".libsonnet") {
+			return nil
+		}
+
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		seen[abs] = true
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fp := fingerprint(abs, info.Size(), info.ModTime().UnixNano(), jpaths)
+
+		if entry, ok := cache.Files[abs]; ok && entry.Fingerprint == fp {
+			return nil
+		}
+
+		imports, err := parseImports(abs, jpaths)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", abs, err)
+		}
+		cache.Files[abs] = fileEntry{Fingerprint: fp, Imports: imports}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop entries for files that no longer exist, so a deleted file's
+	// stale edges don't linger in the cache forever.
+	for path := range cache.Files {
+		if !seen[path] {
+			delete(cache.Files, path)
+		}
+	}
+
+	if err := saveCache(cachePath, cache); err != nil {
+		return nil, err
+	}
+
+	g := Graph{}
+	for importer, entry := range cache.Files {
+		for _, imported := range entry.Imports {
+			g.Add(imported, importer)
+		}
+	}
+	return g, nil
+}
+
+// parseImports parses the Jsonnet file at path and returns the absolute
+// paths of every file it imports (import, importstr, and importbin),
+// resolved the same way the real importer resolves them: relative to
+// path's directory first, falling back to each of jpaths in order.
+func parseImports(path string, jpaths []string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := jsonnet.SnippetToAST(path, string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	var imports []string
+	var visit func(n ast.Node)
+	visit = func(n ast.Node) {
+		if n == nil {
+			return
+		}
+
+		var file string
+		switch imp := n.(type) {
+		case *ast.Import:
+			file = imp.File.Value
+		case *ast.ImportStr:
+			file = imp.File.Value
+		case *ast.ImportBin:
+			file = imp.File.Value
+		}
+		if file != "" && !strings.HasPrefix(file, "<") {
+			imports = append(imports, resolveImport(dir, file, jpaths))
+		}
+
+		for _, child := range toolutils.Children(n) {
+			visit(child)
+		}
+	}
+	visit(node)
+
+	return imports, nil
+}
+
+// resolveImport looks for file relative to dir first, then under each of
+// jpaths in order, mirroring jsonnet.FileImporter's own search order. If
+// file isn't found anywhere (e.g. it will only exist after a `jb vendor`
+// the graph was built without), the dir-relative path is still recorded
+// so the edge isn't silently dropped.
+func resolveImport(dir, file string, jpaths []string) string {
+	candidate := filepath.Clean(filepath.Join(dir, file))
+	if _, err := os.Stat(candidate); err == nil {
+		return candidate
+	}
+
+	for _, jpath := range jpaths {
+		if c := filepath.Clean(filepath.Join(jpath, file)); fileExists(c) {
+			return c
+		}
+	}
+	return candidate
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// fingerprint identifies a file's content, and the search paths used to
+// resolve its imports, without reading the file. This lets an unchanged
+// file skip re-parsing entirely, while still invalidating the cache if
+// jpaths change and would resolve its imports differently.
+func fingerprint(path string, size, mtimeNanos int64, jpaths []string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s:%d:%d:%s", path, size, mtimeNanos, strings.Join(jpaths, ":"))
+	return hex.EncodeToString(h.Sum(nil))
+}